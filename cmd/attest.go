@@ -0,0 +1,138 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"github.com/projectcosign/cosign/pkg"
+	"github.com/projectcosign/cosign/pkg/kms"
+	"github.com/projectcosign/cosign/pkg/rekor"
+)
+
+// attestationTagSuffix is appended to an image's munged digest tag for its
+// attestation, keeping it alongside (but distinct from) its signature tag,
+// which is the munged digest alone.
+const attestationTagSuffix = ".att"
+
+func Attest() *ffcli.Command {
+	var (
+		flagset       = flag.NewFlagSet("cosign attest", flag.ExitOnError)
+		key           = flagset.String("key", "", "path to the private key, or a KMS URI")
+		predicatePath = flagset.String("predicate", "", "path to the predicate file")
+		predicateType = flagset.String("predicate-type", "custom", "the predicate type: slsaprovenance|spdx|link|custom, or a full predicateType URI")
+		rekorURL      = flagset.String("rekor-url", "", "address of the rekor transparency log server to record the attestation on (or set COSIGN_EXPERIMENTAL=1 to use the default public instance)")
+	)
+	return &ffcli.Command{
+		Name:       "attest",
+		ShortUsage: "cosign attest -key <key> -predicate <path> <image uri>",
+		ShortHelp:  "Attest the supplied container image with an in-toto predicate",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *key == "" || *predicatePath == "" {
+				return flag.ErrHelp
+			}
+			if len(args) != 1 {
+				return flag.ErrHelp
+			}
+			return attest(ctx, *key, args[0], *predicatePath, *predicateType, *rekorURL)
+		},
+	}
+}
+
+func attest(ctx context.Context, keyPath, imageRef, predicatePath, predicateType, rekorURL string) error {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return err
+	}
+
+	get, err := remote.Get(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return err
+	}
+
+	predicate, err := ioutil.ReadFile(predicatePath)
+	if err != nil {
+		return fmt.Errorf("reading predicate: %w", err)
+	}
+
+	statement, err := pkg.NewStatement(ref.Name(), get.Descriptor, predicateType, predicate)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return fmt.Errorf("marshaling statement: %w", err)
+	}
+
+	var pass []byte
+	if !kms.IsRef(keyPath) {
+		pass, err = getPass(false)
+		if err != nil {
+			return err
+		}
+	}
+	signer, err := pkg.SignerFromKeyRef(ctx, keyPath, pass)
+	if err != nil {
+		return err
+	}
+
+	env, err := pkg.SignDSSE(ctx, signer, pkg.InTotoPayloadType, payload)
+	if err != nil {
+		return err
+	}
+
+	var extra map[string]string
+	if rekor.IsExperimentalEnabled() || rekorURL != "" {
+		envBytes, err := json.Marshal(env)
+		if err != nil {
+			return fmt.Errorf("marshaling attestation envelope: %w", err)
+		}
+		pub, err := signer.PublicKey(ctx)
+		if err != nil {
+			return err
+		}
+		pubPEM, err := pkg.MarshalPublicKeyPEM(pub)
+		if err != nil {
+			return err
+		}
+		entry, err := rekor.NewClient(rekorURL).UploadDSSE(ctx, envBytes, pubPEM)
+		if err != nil {
+			return fmt.Errorf("uploading to rekor: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "tlog entry created with index: %d\n", entry.LogIndex)
+		if extra, err = entry.Annotations(); err != nil {
+			return err
+		}
+	}
+
+	munged := strings.ReplaceAll(get.Descriptor.Digest.String(), ":", "-")
+	dstTag := ref.Context().Tag(munged + attestationTagSuffix)
+
+	fmt.Println("Pushing attestation to:", dstTag.String())
+	return pkg.UploadAttestation(env, extra, dstTag)
+}
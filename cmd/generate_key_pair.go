@@ -0,0 +1,95 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"github.com/projectcosign/cosign/pkg"
+	"github.com/projectcosign/cosign/pkg/kms"
+)
+
+func GenerateKeyPair() *ffcli.Command {
+	var (
+		flagset = flag.NewFlagSet("cosign generate-key-pair", flag.ExitOnError)
+		kmsRef  = flagset.String("kms", "", "KMS URI to provision the key in, e.g. gcpkms://..., awskms://..., hashivault://... (default: generate a local ed25519 key pair)")
+	)
+	return &ffcli.Command{
+		Name:       "generate-key-pair",
+		ShortUsage: "cosign generate-key-pair [-kms <uri>]",
+		ShortHelp:  "Generate a new signing key pair",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if len(args) != 0 {
+				return flag.ErrHelp
+			}
+			if *kmsRef != "" {
+				return generateKMSKeyPair(ctx, *kmsRef)
+			}
+			return generateLocalKeyPair()
+		},
+	}
+}
+
+// generateKMSKeyPair provisions a new asymmetric signing key in the KMS
+// backend named by kmsRef and prints the URI to sign and verify with.
+func generateKMSKeyPair(ctx context.Context, kmsRef string) error {
+	uri, err := kms.Create(ctx, kmsRef)
+	if err != nil {
+		return fmt.Errorf("provisioning kms key: %w", err)
+	}
+	fmt.Fprintln(os.Stderr, "Successfully provisioned a key in your KMS!")
+	fmt.Println(uri)
+	return nil
+}
+
+// generateLocalKeyPair writes a new password-encrypted ed25519 key pair to
+// cosign.key and cosign.pub in the current directory.
+func generateLocalKeyPair() error {
+	if _, err := os.Stat("cosign.key"); err == nil {
+		return fmt.Errorf("cosign.key already exists")
+	}
+
+	pass, err := getPass(true)
+	if err != nil {
+		return err
+	}
+
+	pub, privPEM, err := pkg.GeneratePrivateKey(pass)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile("cosign.key", privPEM, 0600); err != nil {
+		return fmt.Errorf("writing private key: %w", err)
+	}
+
+	pubPEM, err := pkg.MarshalPublicKeyPEM(pub)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile("cosign.pub", pubPEM, 0644); err != nil {
+		return fmt.Errorf("writing public key: %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Successfully wrote cosign.key and cosign.pub")
+	return nil
+}
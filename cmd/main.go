@@ -0,0 +1,82 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"golang.org/x/term"
+
+	// Register KMS providers for the -key flag.
+	_ "github.com/projectcosign/cosign/pkg/kms/aws"
+	_ "github.com/projectcosign/cosign/pkg/kms/gcp"
+	_ "github.com/projectcosign/cosign/pkg/kms/vault"
+)
+
+func main() {
+	root := &ffcli.Command{
+		Name:       "cosign",
+		ShortUsage: "cosign <subcommand>",
+		Subcommands: []*ffcli.Command{
+			Sign(),
+			Attest(),
+			VerifyAttestation(),
+			Policy(),
+			GenerateKeyPair(),
+		},
+		Exec: func(ctx context.Context, args []string) error {
+			return flag.ErrHelp
+		},
+	}
+
+	if err := root.ParseAndRun(context.Background(), os.Args[1:]); err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintln(os.Stderr, "error:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// getPass reads a password from the terminal, prompting for confirmation
+// when generating a new key.
+func getPass(confirm bool) ([]byte, error) {
+	fmt.Fprint(os.Stderr, "Enter password for private key: ")
+	pw1, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	if !confirm {
+		return pw1, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Enter password for private key again: ")
+	pw2, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(pw1) != string(pw2) {
+		return nil, fmt.Errorf("passwords do not match")
+	}
+	return pw1, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"github.com/projectcosign/cosign/pkg"
+	"github.com/projectcosign/cosign/pkg/policy"
+	"github.com/projectcosign/cosign/pkg/rekor"
+)
+
+func Policy() *ffcli.Command {
+	return &ffcli.Command{
+		Name:        "policy",
+		ShortUsage:  "cosign policy <subcommand>",
+		ShortHelp:   "Evaluate declarative image signing policies",
+		Subcommands: []*ffcli.Command{PolicyVerify()},
+		Exec: func(ctx context.Context, args []string) error {
+			return flag.ErrHelp
+		},
+	}
+}
+
+func PolicyVerify() *ffcli.Command {
+	var (
+		flagset    = flag.NewFlagSet("cosign policy verify", flag.ExitOnError)
+		policyPath = flagset.String("policy", "", "path to the policy.json document to evaluate")
+		rekorURL   = flagset.String("rekor-url", "", "address of the rekor transparency log server to verify inclusion against (or set COSIGN_EXPERIMENTAL=1 to use the default public instance)")
+	)
+	return &ffcli.Command{
+		Name:       "verify",
+		ShortUsage: "cosign policy verify -policy <policy.json> <image uri>",
+		ShortHelp:  "Check that an image satisfies a policy document",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *policyPath == "" || len(args) != 1 {
+				return flag.ErrHelp
+			}
+			return policyVerify(ctx, *policyPath, args[0], *rekorURL)
+		},
+	}
+}
+
+func policyVerify(ctx context.Context, policyPath, imageRef, rekorURL string) error {
+	p, err := policy.Load(policyPath)
+	if err != nil {
+		return err
+	}
+
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return err
+	}
+
+	requirements := p.RequirementsFor(ref)
+
+	// A reject requirement always fails the scope, so there's no need to
+	// hit the registry at all.
+	var sigs []policy.Signature
+	if !hasReject(requirements) {
+		sigs, err = policy.DiscoverSignatures(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("discovering signatures: %w", err)
+		}
+	}
+
+	var tlogVerify policy.TlogVerifierFunc
+	if rekor.IsExperimentalEnabled() || rekorURL != "" {
+		tlogVerify = tlogVerifierFunc(ctx, rekorURL)
+	}
+
+	result := policy.Evaluate(ref, requirements, sigs, pkg.VerifySignature, pkg.ParsePublicKeyPEM, tlogVerify)
+	if !result.Allowed {
+		for _, f := range result.Failures {
+			fmt.Println(f.String())
+		}
+		return fmt.Errorf("image does not satisfy policy: %s", imageRef)
+	}
+
+	fmt.Println("Policy satisfied for:", imageRef)
+	return nil
+}
+
+func hasReject(requirements []policy.Requirement) bool {
+	for _, r := range requirements {
+		if r.Type == "reject" {
+			return true
+		}
+	}
+	return false
+}
+
+// tlogVerifierFunc checks a policy.Signature against the rekor server at
+// rekorURL, the same inclusion/SET check verify-attestation performs: the
+// signature is looked up by the sha256 of its signed payload (how cosign
+// sign recorded it at upload time) and its logged key must match pub.
+func tlogVerifierFunc(ctx context.Context, rekorURL string) policy.TlogVerifierFunc {
+	client := rekor.NewClient(rekorURL)
+	return func(sig policy.Signature, pub crypto.PublicKey) error {
+		return client.VerifyEntry(ctx, sig.Annotations, sha256.Sum256(sig.Payload), pub)
+	}
+}
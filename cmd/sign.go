@@ -18,8 +18,10 @@ package main
 
 import (
 	"context"
-	"crypto/ed25519"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -28,9 +30,12 @@ import (
 
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 	"github.com/peterbourgon/ff/v3/ffcli"
 	"github.com/projectcosign/cosign/pkg"
+	"github.com/projectcosign/cosign/pkg/kms"
+	"github.com/projectcosign/cosign/pkg/rekor"
 )
 
 type annotationsMap struct {
@@ -62,10 +67,13 @@ func Sign() *ffcli.Command {
 	var (
 		flagset     = flag.NewFlagSet("cosign sign", flag.ExitOnError)
 		key         = flagset.String("key", "", "path to the private key")
+		certPath    = flagset.String("cert", "", "path to a PEM-encoded X.509 certificate chain to embed, leaf first (required for -format pkcs7)")
 		upload      = flagset.Bool("upload", true, "whether to upload the signature")
 		payloadPath = flagset.String("payload", "", "path to a payload file to use rather than generating one.")
 		annotations = annotationsMap{}
-		format      = flagset.String("format", "compat", "index|compat")
+		format      = flagset.String("format", "compat", "compat|dsse|pkcs7")
+		rekorURL    = flagset.String("rekor-url", "", "address of the rekor transparency log server to record the signature on (or set COSIGN_EXPERIMENTAL=1 to use the default public instance)")
+		recursive   = flagset.Bool("recursive", false, "if the image is an index, also sign each child manifest's digest in addition to the index digest")
 	)
 	flagset.Var(&annotations, "a", "extra key=value pairs to sign")
 	return &ffcli.Command{
@@ -80,18 +88,56 @@ func Sign() *ffcli.Command {
 			if len(args) != 1 {
 				return flag.ErrHelp
 			}
-			uploader, ok := pkg.Uploaders[*format]
+			env, ok := pkg.Envelopes[*format]
 			if !ok {
 				return fmt.Errorf("unsupported format flag: %s", *format)
 			}
-			return sign(ctx, *key, args[0], *upload, *payloadPath, annotations.annotations, uploader)
+			certChain, err := loadCertChain(*certPath)
+			if err != nil {
+				return err
+			}
+			return sign(ctx, *key, args[0], *upload, *payloadPath, annotations.annotations, env, certChain, *rekorURL, *recursive)
 		},
 	}
 }
 
+// loadCertChain reads a PEM file containing one or more X.509
+// certificates, leaf first. An empty path is not an error: most formats
+// don't need a cert chain at all.
+func loadCertChain(path string) ([]*x509.Certificate, error) {
+	if path == "" {
+		return nil, nil
+	}
+	pemBytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cert chain file: %w", err)
+	}
+
+	var chain []*x509.Certificate
+	for len(pemBytes) > 0 {
+		var block *pem.Block
+		block, pemBytes = pem.Decode(pemBytes)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parsing certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificates found in cert chain file")
+	}
+	return chain, nil
+}
+
 func sign(ctx context.Context, keyPath string,
 	imageRef string, upload bool, payloadPath string,
-	annotations map[string]string, uploader pkg.Uploader) error {
+	annotations map[string]string, env pkg.SignatureEnvelope, certChain []*x509.Certificate, rekorURL string, recursive bool) error {
 	ref, err := name.ParseReference(imageRef)
 	if err != nil {
 		return err
@@ -102,37 +148,117 @@ func sign(ctx context.Context, keyPath string,
 		return err
 	}
 
-	// The payload can be specified via a flag to skip generation.
+	var pass []byte
+	if !kms.IsRef(keyPath) {
+		pass, err = getPass(false)
+		if err != nil {
+			return err
+		}
+	}
+	signer, err := pkg.SignerFromKeyRef(ctx, keyPath, pass)
+	if err != nil {
+		return err
+	}
+
+	if recursive && get.MediaType.IsIndex() {
+		idx, err := get.ImageIndex()
+		if err != nil {
+			return err
+		}
+		indexManifest, err := idx.IndexManifest()
+		if err != nil {
+			return err
+		}
+		for _, child := range indexManifest.Manifests {
+			if !child.MediaType.IsImage() && !child.MediaType.IsIndex() {
+				continue
+			}
+			fmt.Fprintln(os.Stderr, "Signing child manifest:", child.Digest)
+			if err := signDigest(ctx, ref, child.Digest, annotations, signer, upload, env, certChain, rekorURL); err != nil {
+				return fmt.Errorf("signing child manifest %s: %w", child.Digest, err)
+			}
+		}
+	}
+
+	return signDescriptor(ctx, ref, get.Descriptor, payloadPath, annotations, signer, upload, env, certChain, rekorURL)
+}
+
+// signDescriptor signs desc, optionally overriding the generated payload
+// with the contents of payloadPath.
+func signDescriptor(ctx context.Context, ref name.Reference, desc v1.Descriptor, payloadPath string,
+	annotations map[string]string, signer pkg.Signer, upload bool, env pkg.SignatureEnvelope, certChain []*x509.Certificate, rekorURL string) error {
 	var payload []byte
+	var err error
 	if payloadPath != "" {
 		fmt.Fprintln(os.Stderr, "Using payload from:", payloadPath)
 		payload, err = ioutil.ReadFile(payloadPath)
 	} else {
-		payload, err = pkg.Payload(get.Descriptor, annotations)
+		payload, err = pkg.Payload(ref, desc, annotations)
 	}
 	if err != nil {
 		return err
 	}
+	return signPayload(ctx, ref, desc.Digest, payload, signer, upload, env, certChain, rekorURL)
+}
 
-	pass, err := getPass(false)
+// signDigest signs the generated payload for digest, a child manifest of
+// an index.
+func signDigest(ctx context.Context, ref name.Reference, digest v1.Hash,
+	annotations map[string]string, signer pkg.Signer, upload bool, env pkg.SignatureEnvelope, certChain []*x509.Certificate, rekorURL string) error {
+	payload, err := pkg.Payload(ref, v1.Descriptor{Digest: digest}, annotations)
 	if err != nil {
 		return err
 	}
-	pk, err := pkg.LoadPrivateKey(keyPath, pass)
+	return signPayload(ctx, ref, digest, payload, signer, upload, env, certChain, rekorURL)
+}
+
+func signPayload(ctx context.Context, ref name.Reference, digest v1.Hash, payload []byte,
+	signer pkg.Signer, upload bool, env pkg.SignatureEnvelope, certChain []*x509.Certificate, rekorURL string) error {
+	blob, signature, annotations, err := env.Marshal(ctx, signer, payload, certChain)
 	if err != nil {
-		return err
+		return fmt.Errorf("marshaling %s envelope: %w", env.MediaType(), err)
 	}
-	signature := ed25519.Sign(pk, payload)
 
 	if !upload {
 		fmt.Println(base64.StdEncoding.EncodeToString(signature))
 		return nil
 	}
 
+	if rekor.IsExperimentalEnabled() || rekorURL != "" {
+		entry, err := uploadToRekor(ctx, signer, payload, signature, rekorURL)
+		if err != nil {
+			return fmt.Errorf("uploading to rekor: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "tlog entry created with index: %d\n", entry.LogIndex)
+		extra, err := entry.Annotations()
+		if err != nil {
+			return err
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		for k, v := range extra {
+			annotations[k] = v
+		}
+	}
+
 	// sha256:... -> sha256-...
-	munged := strings.ReplaceAll(get.Descriptor.Digest.String(), ":", "-")
+	munged := strings.ReplaceAll(digest.String(), ":", "-")
 	dstTag := ref.Context().Tag(munged)
 
 	fmt.Fprintln(os.Stderr, "Pushing signature to:", dstTag.String())
-	return uploader.Upload(signature, payload, dstTag)
+	return pkg.PublishSignature(blob, env.MediaType(), annotations, dstTag)
+}
+
+// uploadToRekor records payload's signature as a new Rekor log entry.
+func uploadToRekor(ctx context.Context, signer pkg.Signer, payload, signature []byte, rekorURL string) (*rekor.LogEntry, error) {
+	pub, err := signer.PublicKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pubPEM, err := pkg.MarshalPublicKeyPEM(pub)
+	if err != nil {
+		return nil, err
+	}
+	return rekor.NewClient(rekorURL).UploadHashedRekord(ctx, payload, signature, pubPEM)
 }
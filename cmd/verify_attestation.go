@@ -0,0 +1,159 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/peterbourgon/ff/v3/ffcli"
+	"github.com/projectcosign/cosign/pkg"
+	"github.com/projectcosign/cosign/pkg/rekor"
+)
+
+func VerifyAttestation() *ffcli.Command {
+	var (
+		flagset  = flag.NewFlagSet("cosign verify-attestation", flag.ExitOnError)
+		key      = flagset.String("key", "", "path to the public key, or a KMS URI")
+		rekorURL = flagset.String("rekor-url", "", "address of the rekor transparency log server to verify inclusion against (or set COSIGN_EXPERIMENTAL=1 to use the default public instance)")
+	)
+	return &ffcli.Command{
+		Name:       "verify-attestation",
+		ShortUsage: "cosign verify-attestation -key <key> <image uri>",
+		ShortHelp:  "Verify an attestation attached to the supplied container image and print its predicate",
+		FlagSet:    flagset,
+		Exec: func(ctx context.Context, args []string) error {
+			if *key == "" || len(args) != 1 {
+				return flag.ErrHelp
+			}
+			return verifyAttestation(ctx, *key, args[0], *rekorURL)
+		},
+	}
+}
+
+func verifyAttestation(ctx context.Context, keyPath, imageRef, rekorURL string) error {
+	ref, err := name.ParseReference(imageRef)
+	if err != nil {
+		return err
+	}
+
+	get, err := remote.Get(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return err
+	}
+
+	munged := strings.ReplaceAll(get.Descriptor.Digest.String(), ":", "-")
+	attTag := ref.Context().Tag(munged + attestationTagSuffix)
+
+	img, err := remote.Image(attTag, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return fmt.Errorf("fetching attestation: %w", err)
+	}
+	manifest, err := img.Manifest()
+	if err != nil {
+		return err
+	}
+	if len(manifest.Layers) != 1 {
+		return fmt.Errorf("expected exactly one attestation layer, got %d", len(manifest.Layers))
+	}
+	layers, err := img.Layers()
+	if err != nil {
+		return err
+	}
+
+	rc, err := layers[0].Uncompressed()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	envBytes, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return fmt.Errorf("reading attestation envelope: %w", err)
+	}
+
+	var env pkg.Envelope
+	if err := json.Unmarshal(envBytes, &env); err != nil {
+		return fmt.Errorf("decoding attestation envelope: %w", err)
+	}
+	if len(env.Signatures) == 0 {
+		return fmt.Errorf("attestation envelope has no signatures")
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return fmt.Errorf("decoding attestation payload: %w", err)
+	}
+
+	pub, err := pkg.PublicKeyFromKeyRef(ctx, keyPath)
+	if err != nil {
+		return err
+	}
+
+	var verifyErr error
+	for _, sig := range env.Signatures {
+		sigBytes, err := base64.StdEncoding.DecodeString(sig.Sig)
+		if err != nil {
+			verifyErr = err
+			continue
+		}
+		if verifyErr = pkg.VerifySignature(pub, pkg.PAE(env.PayloadType, payload), sigBytes); verifyErr == nil {
+			break
+		}
+	}
+	if verifyErr != nil {
+		return fmt.Errorf("verifying attestation signature: %w", verifyErr)
+	}
+
+	if rekor.IsExperimentalEnabled() || rekorURL != "" {
+		if err := verifyRekorEntry(ctx, manifest.Layers[0].Annotations, envBytes, pub, rekorURL); err != nil {
+			return fmt.Errorf("verifying transparency log inclusion: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "tlog entry verified")
+	}
+
+	fmt.Fprintln(os.Stderr, "Verified OK")
+
+	var stmt pkg.InTotoStatement
+	if err := json.Unmarshal(payload, &stmt); err != nil {
+		return fmt.Errorf("decoding in-toto statement: %w", err)
+	}
+	out, err := json.MarshalIndent(stmt.Predicate, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// verifyRekorEntry finds the Rekor log entry for envBytes and checks that
+// the log really included it under the same key that verified the
+// signature above — so a signature whose key was never recorded in the
+// log is rejected even if it verifies offline.
+func verifyRekorEntry(ctx context.Context, annotations map[string]string, envBytes []byte, pub crypto.PublicKey, rekorURL string) error {
+	return rekor.NewClient(rekorURL).VerifyEntry(ctx, annotations, sha256.Sum256(envBytes), pub)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	// InTotoPayloadType is the DSSE payloadType for in-toto attestations.
+	InTotoPayloadType = "application/vnd.in-toto+json"
+
+	inTotoStatementType = "https://in-toto.io/Statement/v0.1"
+)
+
+// PredicateTypes maps the short names accepted by the -predicate-type flag
+// to their full in-toto predicateType URIs. Anything not found here is
+// assumed to already be a predicateType URI.
+var PredicateTypes = map[string]string{
+	"slsaprovenance": "https://slsa.dev/provenance/v0.2",
+	"spdx":           "https://spdx.dev/Document",
+	"link":           "https://in-toto.io/Link/v1",
+}
+
+// InTotoStatement is the outermost in-toto attestation layer: a typed,
+// subject-bound wrapper around an arbitrary predicate.
+type InTotoStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []InTotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// InTotoSubject identifies the artifact an attestation's predicate is
+// about, here always the image being attested.
+type InTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// ResolvePredicateType expands a short predicate-type name (e.g.
+// "slsaprovenance") into its full URI, passing anything unrecognized
+// through unchanged so custom predicate types keep working.
+func ResolvePredicateType(short string) string {
+	if full, ok := PredicateTypes[short]; ok {
+		return full
+	}
+	return short
+}
+
+// NewStatement wraps predicate (the raw bytes of a predicate file) into an
+// in-toto Statement whose subject is imageRef at desc's digest.
+func NewStatement(imageRef string, desc v1.Descriptor, predicateType string, predicate []byte) (*InTotoStatement, error) {
+	if !json.Valid(predicate) {
+		return nil, fmt.Errorf("predicate is not valid JSON")
+	}
+
+	parts := strings.SplitN(desc.Digest.String(), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid digest: %s", desc.Digest.String())
+	}
+
+	return &InTotoStatement{
+		Type: inTotoStatementType,
+		Subject: []InTotoSubject{
+			{
+				Name:   imageRef,
+				Digest: map[string]string{parts[0]: parts[1]},
+			},
+		},
+		PredicateType: ResolvePredicateType(predicateType),
+		Predicate:     json.RawMessage(predicate),
+	}, nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestResolvePredicateType(t *testing.T) {
+	tests := []struct {
+		short string
+		want  string
+	}{
+		{"slsaprovenance", "https://slsa.dev/provenance/v0.2"},
+		{"spdx", "https://spdx.dev/Document"},
+		{"link", "https://in-toto.io/Link/v1"},
+		{"https://example.com/custom/v1", "https://example.com/custom/v1"},
+	}
+	for _, tt := range tests {
+		if got := ResolvePredicateType(tt.short); got != tt.want {
+			t.Errorf("ResolvePredicateType(%q) = %q, want %q", tt.short, got, tt.want)
+		}
+	}
+}
+
+func TestNewStatement(t *testing.T) {
+	desc := v1.Descriptor{Digest: v1.Hash{Algorithm: "sha256", Hex: "deadbeef"}}
+
+	stmt, err := NewStatement("example.com/repo:latest", desc, "slsaprovenance", []byte(`{"builder":{}}`))
+	if err != nil {
+		t.Fatalf("NewStatement() error: %v", err)
+	}
+
+	if want := "https://in-toto.io/Statement/v0.1"; stmt.Type != want {
+		t.Errorf("Type = %q, want %q", stmt.Type, want)
+	}
+	if want := "https://slsa.dev/provenance/v0.2"; stmt.PredicateType != want {
+		t.Errorf("PredicateType = %q, want %q", stmt.PredicateType, want)
+	}
+	if len(stmt.Subject) != 1 {
+		t.Fatalf("len(Subject) = %d, want 1", len(stmt.Subject))
+	}
+	if want := "example.com/repo:latest"; stmt.Subject[0].Name != want {
+		t.Errorf("Subject[0].Name = %q, want %q", stmt.Subject[0].Name, want)
+	}
+	if want := "deadbeef"; stmt.Subject[0].Digest["sha256"] != want {
+		t.Errorf("Subject[0].Digest[\"sha256\"] = %q, want %q", stmt.Subject[0].Digest["sha256"], want)
+	}
+
+	if _, err := NewStatement("example.com/repo:latest", desc, "custom", []byte("not json")); err == nil {
+		t.Error("NewStatement() with an invalid predicate succeeded, want error")
+	}
+}
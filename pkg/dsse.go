@@ -0,0 +1,64 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+)
+
+// Envelope is a DSSE (github.com/secure-systems-lab/dsse) envelope: a
+// payload of some type, and one or more signatures over its PAE encoding.
+type Envelope struct {
+	PayloadType string              `json:"payloadType"`
+	Payload     string              `json:"payload"`
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+// EnvelopeSignature is a single signature over an Envelope's PAE encoding.
+type EnvelopeSignature struct {
+	Sig string `json:"sig"`
+}
+
+// PAE is DSSE's "Pre-Authentication Encoding": a length-prefixed encoding
+// of the payload type and body that's what actually gets signed, so a
+// signature can't be replayed against a payload of a different type.
+func PAE(payloadType string, payload []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))
+	buf.Write(payload)
+	return buf.Bytes()
+}
+
+// SignDSSE wraps payload (of the given payloadType) in a DSSE envelope,
+// signing its PAE encoding with signer.
+func SignDSSE(ctx context.Context, signer Signer, payloadType string, payload []byte) (*Envelope, error) {
+	sig, err := signer.Sign(ctx, PAE(payloadType, payload))
+	if err != nil {
+		return nil, fmt.Errorf("signing dsse envelope: %w", err)
+	}
+
+	return &Envelope{
+		PayloadType: payloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []EnvelopeSignature{
+			{Sig: base64.StdEncoding.EncodeToString(sig)},
+		},
+	}, nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// SignatureEnvelope knows how to wrap a signed payload for upload to a
+// registry as a single-layer OCI artifact. Each envelope picks its own
+// layer media type and its own way of carrying the signed payload and
+// signature (a payload annotation beside a raw signature, a DSSE envelope,
+// or an envelope with an X.509 cert chain), so cosign isn't hard-coded to
+// raw ed25519 detached signatures.
+type SignatureEnvelope interface {
+	// MediaType is the OCI layer media type this envelope is uploaded as.
+	MediaType() types.MediaType
+
+	// Marshal signs payload with signer and returns the envelope's
+	// on-disk layer contents, the raw signature (for recording in the
+	// transparency log), and any extra OCI annotations the envelope
+	// needs alongside the layer. certChain is only consulted by
+	// envelopes that carry an X.509 identity (currently pkcs7); other
+	// envelopes ignore it.
+	Marshal(ctx context.Context, signer Signer, payload []byte, certChain []*x509.Certificate) (blob, signature []byte, annotations map[string]string, err error)
+
+	// Unmarshal recovers a previously-published signature from blob (the
+	// layer contents) and annotations, the inverse of Marshal. It
+	// returns the signed payload, the exact bytes the signature verifies
+	// (which can differ from payload, e.g. DSSE's PAE encoding), and the
+	// signature itself.
+	Unmarshal(blob []byte, annotations map[string]string) (payload, signed, signature []byte, err error)
+}
+
+// EnvelopeForMediaType returns the registered envelope whose MediaType
+// matches mt, for decoding a layer whose format isn't known ahead of
+// time (e.g. when discovering signatures of any format on an image).
+func EnvelopeForMediaType(mt types.MediaType) (SignatureEnvelope, bool) {
+	for _, env := range Envelopes {
+		if env.MediaType() == mt {
+			return env, true
+		}
+	}
+	return nil, false
+}
+
+// Envelopes holds the registered signature envelope formats, keyed by
+// the -format flag value cosign sign accepts.
+var Envelopes = map[string]SignatureEnvelope{
+	"compat": &compatEnvelope{},
+	"dsse":   &dsseSignatureEnvelope{},
+	"pkcs7":  &pkcs7Envelope{},
+}
+
+// compatEnvelope is cosign's original signature format: the layer is the
+// base64-encoded raw signature, and the signed payload travels alongside
+// it in a layer annotation.
+type compatEnvelope struct{}
+
+func (*compatEnvelope) MediaType() types.MediaType {
+	return types.MediaType("application/vnd.dev.cosign.simplesigning.v1+json")
+}
+
+func (*compatEnvelope) Marshal(ctx context.Context, signer Signer, payload []byte, _ []*x509.Certificate) ([]byte, []byte, map[string]string, error) {
+	sig, err := signer.Sign(ctx, payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	blob := []byte(base64.StdEncoding.EncodeToString(sig))
+	annotations := map[string]string{SignatureAnnotationKey: base64.StdEncoding.EncodeToString(payload)}
+	return blob, sig, annotations, nil
+}
+
+func (*compatEnvelope) Unmarshal(blob []byte, annotations map[string]string) ([]byte, []byte, []byte, error) {
+	payloadB64, ok := annotations[SignatureAnnotationKey]
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("missing %s annotation", SignatureAnnotationKey)
+	}
+	payload, err := base64.StdEncoding.DecodeString(payloadB64)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding payload annotation: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(blob))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding signature: %w", err)
+	}
+	return payload, payload, sig, nil
+}
+
+// simpleSigningPayloadType is the DSSE payload type recorded for a plain
+// signed image digest, naming the same payload shape as compatEnvelope's
+// layer media type.
+const simpleSigningPayloadType = "application/vnd.dev.cosign.simplesigning.v1+json"
+
+// dsseSignatureEnvelope wraps the payload in a DSSE envelope (see
+// SignDSSE), the same envelope shape cosign attest uses for in-toto
+// attestations.
+type dsseSignatureEnvelope struct{}
+
+func (*dsseSignatureEnvelope) MediaType() types.MediaType {
+	return types.MediaType("application/vnd.dev.cosignproject.cosign.dsse.v1+json")
+}
+
+func (*dsseSignatureEnvelope) Marshal(ctx context.Context, signer Signer, payload []byte, _ []*x509.Certificate) ([]byte, []byte, map[string]string, error) {
+	env, err := SignDSSE(ctx, signer, simpleSigningPayloadType, payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	blob, err := json.Marshal(env)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("marshaling dsse envelope: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding dsse signature: %w", err)
+	}
+	return blob, sig, nil, nil
+}
+
+func (*dsseSignatureEnvelope) Unmarshal(blob []byte, _ map[string]string) ([]byte, []byte, []byte, error) {
+	var env Envelope
+	if err := json.Unmarshal(blob, &env); err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing dsse envelope: %w", err)
+	}
+	if len(env.Signatures) == 0 {
+		return nil, nil, nil, errors.New("dsse envelope has no signatures")
+	}
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding dsse payload: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding dsse signature: %w", err)
+	}
+	return payload, PAE(env.PayloadType, payload), sig, nil
+}
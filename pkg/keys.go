@@ -0,0 +1,124 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// privateKeyPemType is the PEM block type cosign writes its encrypted
+	// ed25519 private keys as.
+	privateKeyPemType = "ENCRYPTED COSIGN PRIVATE KEY"
+
+	sigNonceSize = 24
+)
+
+// EncryptedPrivateKey is the on-disk representation of a password-wrapped
+// ed25519 private key: an scrypt salt, a secretbox nonce, and the sealed key.
+type EncryptedPrivateKey struct {
+	Salt       []byte
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// GeneratePrivateKey creates a new ed25519 keypair, encrypting the private
+// half with pass before returning its PEM encoding.
+func GeneratePrivateKey(pass []byte) (ed25519.PublicKey, []byte, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating keypair: %w", err)
+	}
+
+	encBytes, err := encrypt(priv, pass)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pub, pem.EncodeToMemory(&pem.Block{
+		Bytes: encBytes,
+		Type:  privateKeyPemType,
+	}), nil
+}
+
+func encrypt(priv ed25519.PrivateKey, pass []byte) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("reading salt: %w", err)
+	}
+	key, err := scrypt.Key(pass, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	var keyArray [32]byte
+	copy(keyArray[:], key)
+
+	var nonceArray [sigNonceSize]byte
+	if _, err := rand.Read(nonceArray[:]); err != nil {
+		return nil, fmt.Errorf("reading nonce: %w", err)
+	}
+
+	out := secretbox.Seal(nonceArray[:], priv, &nonceArray, &keyArray)
+	return append(salt, out...), nil
+}
+
+// LoadPrivateKey loads an ed25519 private key from a PEM-encoded,
+// password-protected file at path.
+func LoadPrivateKey(path string, pass []byte) (ed25519.PrivateKey, error) {
+	kb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading key file: %w", err)
+	}
+
+	p, _ := pem.Decode(kb)
+	if p == nil {
+		return nil, errors.New("invalid pem block")
+	}
+	if p.Type != privateKeyPemType {
+		return nil, fmt.Errorf("unexpected pem block type: %s", p.Type)
+	}
+
+	if len(p.Bytes) < 32+sigNonceSize {
+		return nil, errors.New("encrypted private key is truncated")
+	}
+	salt, rest := p.Bytes[:32], p.Bytes[32:]
+
+	key, err := scrypt.Key(pass, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	var keyArray [32]byte
+	copy(keyArray[:], key)
+
+	var nonce [sigNonceSize]byte
+	copy(nonce[:], rest[:sigNonceSize])
+
+	out, ok := secretbox.Open(nil, rest[sigNonceSize:], &nonce, &keyArray)
+	if !ok {
+		return nil, errors.New("incorrect password for decrypting private key")
+	}
+	return ed25519.PrivateKey(out), nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package aws implements a kms.Signer backed by AWS KMS, for keys named by
+// an "awskms://" reference, e.g. awskms://alias/my-alias or
+// awskms://arn:aws:kms:us-east-2:111122223333:key/1234abcd-...
+package aws
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	awskms "github.com/aws/aws-sdk-go/service/kms"
+	"github.com/projectcosign/cosign/pkg/kms"
+)
+
+const referencePrefix = "awskms://"
+
+func init() {
+	kms.AddProvider(referencePrefix, func(ctx context.Context, keyPath string) (kms.Signer, error) {
+		sess, err := session.NewSession()
+		if err != nil {
+			return nil, fmt.Errorf("creating aws session: %w", err)
+		}
+		return &signer{client: awskms.New(sess), keyID: keyPath}, nil
+	})
+	kms.AddCreator(referencePrefix, createKey)
+}
+
+// createKey provisions a new asymmetric customer master key (an EC
+// P-256 key, cosign's default for KMS-held keys) for signing. If keyPath
+// is non-empty it's used as an alias for the new key; otherwise the
+// returned reference names the key's ID directly.
+func createKey(ctx context.Context, keyPath string) (string, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("creating aws session: %w", err)
+	}
+	client := awskms.New(sess)
+
+	out, err := client.CreateKeyWithContext(ctx, &awskms.CreateKeyInput{
+		KeyUsage:              aws.String(awskms.KeyUsageTypeSignVerify),
+		CustomerMasterKeySpec: aws.String(awskms.CustomerMasterKeySpecEccNistP256),
+	})
+	if err != nil {
+		return "", fmt.Errorf("aws kms: create key: %w", err)
+	}
+	keyID := aws.StringValue(out.KeyMetadata.KeyId)
+
+	if keyPath == "" {
+		return keyID, nil
+	}
+	alias := "alias/" + keyPath
+	if _, err := client.CreateAliasWithContext(ctx, &awskms.CreateAliasInput{
+		AliasName:   aws.String(alias),
+		TargetKeyId: aws.String(keyID),
+	}); err != nil {
+		return "", fmt.Errorf("aws kms: create alias: %w", err)
+	}
+	return alias, nil
+}
+
+// signer signs payloads using an asymmetric customer master key held in
+// AWS KMS.
+type signer struct {
+	client *awskms.KMS
+	keyID  string
+}
+
+func (s *signer) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	out, err := s.client.SignWithContext(ctx, &awskms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          payload,
+		MessageType:      aws.String(awskms.MessageTypeRaw),
+		SigningAlgorithm: aws.String(awskms.SigningAlgorithmSpecEcdsaSha256),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws kms: sign: %w", err)
+	}
+	return out.Signature, nil
+}
+
+func (s *signer) PublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	out, err := s.client.GetPublicKeyWithContext(ctx, &awskms.GetPublicKeyInput{
+		KeyId: aws.String(s.keyID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws kms: get public key: %w", err)
+	}
+	return x509.ParsePKIXPublicKey(out.PublicKey)
+}
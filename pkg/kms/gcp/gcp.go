@@ -0,0 +1,125 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package gcp implements a kms.Signer backed by Google Cloud KMS, for
+// keys named by a "gcpkms://" reference, e.g.
+// gcpkms://projects/P/locations/L/keyRings/R/cryptoKeys/K/cryptoKeyVersions/V
+package gcp
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	kmsapi "cloud.google.com/go/kms/apiv1"
+	"github.com/projectcosign/cosign/pkg/kms"
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+)
+
+const referencePrefix = "gcpkms://"
+
+func init() {
+	kms.AddProvider(referencePrefix, func(ctx context.Context, keyPath string) (kms.Signer, error) {
+		client, err := kmsapi.NewKeyManagementClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("creating gcp kms client: %w", err)
+		}
+		return &signer{client: client, keyVersionName: keyPath}, nil
+	})
+	kms.AddCreator(referencePrefix, createKey)
+}
+
+// createKey provisions a new asymmetric signing key (an EC P-256 key,
+// cosign's default for KMS-held keys) under keyPath, which must name an
+// as-yet-unused cryptoKeys resource, e.g.
+// projects/P/locations/L/keyRings/R/cryptoKeys/K. It returns a reference
+// to the resulting key's first version, ready to sign with.
+func createKey(ctx context.Context, keyPath string) (string, error) {
+	client, err := kmsapi.NewKeyManagementClient(ctx)
+	if err != nil {
+		return "", fmt.Errorf("creating gcp kms client: %w", err)
+	}
+	defer client.Close()
+
+	parent, cryptoKeyID, err := splitCryptoKeyPath(keyPath)
+	if err != nil {
+		return "", err
+	}
+
+	ck, err := client.CreateCryptoKey(ctx, &kmspb.CreateCryptoKeyRequest{
+		Parent:      parent,
+		CryptoKeyId: cryptoKeyID,
+		CryptoKey: &kmspb.CryptoKey{
+			Purpose: kmspb.CryptoKey_ASYMMETRIC_SIGN,
+			VersionTemplate: &kmspb.CryptoKeyVersionTemplate{
+				Algorithm: kmspb.CryptoKeyVersion_EC_SIGN_P256_SHA256,
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("gcp kms: create crypto key: %w", err)
+	}
+	return ck.Name + "/cryptoKeyVersions/1", nil
+}
+
+// splitCryptoKeyPath splits a cryptoKeys resource name into its parent key
+// ring and crypto key ID, the two pieces CreateCryptoKey takes separately.
+func splitCryptoKeyPath(keyPath string) (parent, cryptoKeyID string, err error) {
+	const sep = "/cryptoKeys/"
+	idx := strings.LastIndex(keyPath, sep)
+	if idx < 0 {
+		return "", "", fmt.Errorf("gcp kms: expected .../cryptoKeys/<id>, got %s", keyPath)
+	}
+	return keyPath[:idx], keyPath[idx+len(sep):], nil
+}
+
+// signer signs payloads using an asymmetric key version held in Google
+// Cloud KMS. keyVersionName is the full cryptoKeyVersion resource name.
+type signer struct {
+	client         *kmsapi.KeyManagementClient
+	keyVersionName string
+}
+
+func (s *signer) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	digest := sha256.Sum256(payload)
+	resp, err := s.client.AsymmetricSign(ctx, &kmspb.AsymmetricSignRequest{
+		Name: s.keyVersionName,
+		Digest: &kmspb.Digest{
+			Digest: &kmspb.Digest_Sha256{Sha256: digest[:]},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcp kms: asymmetric sign: %w", err)
+	}
+	return resp.Signature, nil
+}
+
+func (s *signer) PublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	resp, err := s.client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: s.keyVersionName})
+	if err != nil {
+		return nil, fmt.Errorf("gcp kms: get public key: %w", err)
+	}
+
+	block, _ := pem.Decode([]byte(resp.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("gcp kms: invalid public key pem for %s", s.keyVersionName)
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kms provides a scheme-based registry of remote signing backends,
+// so cosign's -key flag can name a KMS-held key (e.g. gcpkms://...,
+// awskms://..., hashivault://...) instead of a file on disk.
+package kms
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"strings"
+)
+
+// Signer is a key held in a remote KMS that cosign can sign payloads with
+// without ever seeing the private key material.
+type Signer interface {
+	// Sign signs payload and returns the raw signature bytes.
+	Sign(ctx context.Context, payload []byte) ([]byte, error)
+	// PublicKey returns the public half of the signing key.
+	PublicKey(ctx context.Context) (crypto.PublicKey, error)
+}
+
+// ProviderFunc constructs a Signer from the part of a KMS reference after
+// the scheme prefix, e.g.
+// "projects/P/locations/L/keyRings/R/cryptoKeys/K/cryptoKeyVersions/V" for
+// "gcpkms://projects/P/...".
+type ProviderFunc func(ctx context.Context, keyPath string) (Signer, error)
+
+// CreatorFunc provisions a new asymmetric signing key in a KMS backend,
+// named by the part of a KMS reference after the scheme prefix, and
+// returns that same part pointing at the key (or key version) it
+// created, for generate-key-pair -kms.
+type CreatorFunc func(ctx context.Context, keyPath string) (string, error)
+
+// providers holds the registered KMS schemes, keyed by their "scheme://"
+// prefix (including the "://").
+var providers = map[string]ProviderFunc{}
+
+// creators holds the registered key-provisioning funcs, keyed the same
+// way as providers. Not every provider registers one.
+var creators = map[string]CreatorFunc{}
+
+// AddProvider registers a KMS backend under the given "scheme://" prefix.
+// Provider packages call this from an init func so importing them for
+// side effect is enough to make them available.
+func AddProvider(prefix string, p ProviderFunc) {
+	providers[prefix] = p
+}
+
+// AddCreator registers a key-provisioning func under the given
+// "scheme://" prefix, alongside AddProvider.
+func AddCreator(prefix string, c CreatorFunc) {
+	creators[prefix] = c
+}
+
+// IsRef returns true if ref names a key in a registered KMS, rather than a
+// path on disk.
+func IsRef(ref string) bool {
+	for prefix := range providers {
+		if strings.HasPrefix(ref, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get resolves ref to a Signer using the provider registered for its
+// scheme prefix.
+func Get(ctx context.Context, ref string) (Signer, error) {
+	for prefix, p := range providers {
+		if strings.HasPrefix(ref, prefix) {
+			return p(ctx, strings.TrimPrefix(ref, prefix))
+		}
+	}
+	return nil, fmt.Errorf("kms: unsupported key reference: %s", ref)
+}
+
+// Create provisions a new asymmetric signing key using the creator
+// registered for ref's scheme prefix, and returns the full "scheme://..."
+// reference to the key it created.
+func Create(ctx context.Context, ref string) (string, error) {
+	for prefix, c := range creators {
+		if strings.HasPrefix(ref, prefix) {
+			keyPath, err := c(ctx, strings.TrimPrefix(ref, prefix))
+			if err != nil {
+				return "", err
+			}
+			return prefix + keyPath, nil
+		}
+	}
+	return "", fmt.Errorf("kms: unsupported key reference: %s", ref)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kms
+
+import (
+	"context"
+	"crypto"
+	"testing"
+)
+
+const testPrefix = "testkms://"
+
+type fakeSigner struct{ keyPath string }
+
+func (f *fakeSigner) Sign(_ context.Context, payload []byte) ([]byte, error) { return payload, nil }
+func (f *fakeSigner) PublicKey(_ context.Context) (crypto.PublicKey, error)  { return f.keyPath, nil }
+
+func TestProviderRegistry(t *testing.T) {
+	AddProvider(testPrefix, func(_ context.Context, keyPath string) (Signer, error) {
+		return &fakeSigner{keyPath: keyPath}, nil
+	})
+	defer delete(providers, testPrefix)
+
+	if !IsRef(testPrefix + "my-key") {
+		t.Errorf("IsRef(%q) = false, want true", testPrefix+"my-key")
+	}
+	if IsRef("not-a-kms-ref") {
+		t.Error("IsRef(\"not-a-kms-ref\") = true, want false")
+	}
+
+	signer, err := Get(context.Background(), testPrefix+"my-key")
+	if err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	pub, err := signer.PublicKey(context.Background())
+	if err != nil {
+		t.Fatalf("PublicKey() error: %v", err)
+	}
+	if pub != "my-key" {
+		t.Errorf("PublicKey() = %v, want %q (the prefix should be stripped before reaching the provider)", pub, "my-key")
+	}
+
+	if _, err := Get(context.Background(), "unregisteredkms://foo"); err == nil {
+		t.Error("Get() with an unregistered scheme succeeded, want error")
+	}
+}
+
+func TestCreatorRegistry(t *testing.T) {
+	AddCreator(testPrefix, func(_ context.Context, keyPath string) (string, error) {
+		return keyPath + "/v1", nil
+	})
+	defer delete(creators, testPrefix)
+
+	got, err := Create(context.Background(), testPrefix+"my-key")
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	want := testPrefix + "my-key/v1"
+	if got != want {
+		t.Errorf("Create() = %q, want %q", got, want)
+	}
+
+	if _, err := Create(context.Background(), "unregisteredkms://foo"); err == nil {
+		t.Error("Create() with an unregistered scheme succeeded, want error")
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// SimpleContainerImage is the "simple signing" payload format cosign signs,
+// modeled on containers/image's signature format.
+type SimpleContainerImage struct {
+	Critical Critical               `json:"critical"`
+	Optional map[string]interface{} `json:"optional,omitempty"`
+}
+
+// Critical fields that MUST be present and verified for a signature to be
+// considered valid.
+type Critical struct {
+	Identity Identity `json:"identity"`
+	Image    Image    `json:"image"`
+	Type     string   `json:"type"`
+}
+
+// Identity identifies the image this payload was generated for.
+type Identity struct {
+	DockerReference string `json:"docker-reference,omitempty"`
+}
+
+// Image pins the payload to a specific manifest digest.
+type Image struct {
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+// Payload generates the bytes that get signed for desc, naming ref as the
+// identity the signature is scoped to (containers/image's docker-reference),
+// and folding in any extra annotations as "optional" fields.
+func Payload(ref name.Reference, desc v1.Descriptor, annotations map[string]string) ([]byte, error) {
+	simpleContainerImage := SimpleContainerImage{
+		Critical: Critical{
+			Identity: Identity{
+				DockerReference: ref.Name(),
+			},
+			Image: Image{
+				DockerManifestDigest: desc.Digest.String(),
+			},
+			Type: "cosign container image signature",
+		},
+		Optional: map[string]interface{}{},
+	}
+	for k, v := range annotations {
+		simpleContainerImage.Optional[k] = v
+	}
+	return json.Marshal(simpleContainerImage)
+}
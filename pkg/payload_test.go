@@ -0,0 +1,58 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+func TestPayload(t *testing.T) {
+	ref, err := name.ParseReference("example.com/repo:latest")
+	if err != nil {
+		t.Fatalf("parsing reference: %v", err)
+	}
+	digest := v1.Hash{Algorithm: "sha256", Hex: "deadbeef"}
+
+	// signDigest (used for -recursive's child manifests) only has a bare
+	// digest to work with, not a full descriptor: Payload must still
+	// record a usable docker-reference and digest from that alone.
+	desc := v1.Descriptor{Digest: digest}
+
+	b, err := Payload(ref, desc, map[string]string{"foo": "bar"})
+	if err != nil {
+		t.Fatalf("Payload() error: %v", err)
+	}
+
+	var got SimpleContainerImage
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("unmarshaling payload: %v", err)
+	}
+
+	if want := "example.com/repo:latest"; got.Critical.Identity.DockerReference != want {
+		t.Errorf("DockerReference = %q, want %q", got.Critical.Identity.DockerReference, want)
+	}
+	if want := digest.String(); got.Critical.Image.DockerManifestDigest != want {
+		t.Errorf("DockerManifestDigest = %q, want %q", got.Critical.Image.DockerManifestDigest, want)
+	}
+	if got.Optional["foo"] != "bar" {
+		t.Errorf("Optional[\"foo\"] = %v, want %q", got.Optional["foo"], "bar")
+	}
+}
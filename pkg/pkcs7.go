@@ -0,0 +1,94 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// pkcs7SignedData is a minimal, ASN.1-tagged subset of a PKCS#7/CMS
+// SignedData structure: just enough to carry a signed payload, the
+// algorithm that produced its signature, and the X.509 chain that
+// verifies it. It doesn't model the full CMS ASN.1 module (content-type
+// OIDs, SignerInfo sets, attributes, etc.) since cosign's verifier is
+// the only consumer and doesn't need interop with a general CMS parser.
+type pkcs7SignedData struct {
+	Algorithm    string `asn1:"utf8"`
+	Payload      []byte
+	Signature    []byte
+	Certificates [][]byte
+}
+
+// pkcs7Envelope wraps the payload and its signature alongside an X.509
+// certificate chain, for signers whose key comes from an existing
+// corporate CA rather than a fresh ed25519 keypair from cosign
+// generate-key-pair.
+type pkcs7Envelope struct{}
+
+func (*pkcs7Envelope) MediaType() types.MediaType {
+	return types.MediaType("application/vnd.dev.cosignproject.cosign.pkcs7")
+}
+
+func (*pkcs7Envelope) Marshal(ctx context.Context, signer Signer, payload []byte, certChain []*x509.Certificate) ([]byte, []byte, map[string]string, error) {
+	if len(certChain) == 0 {
+		return nil, nil, nil, errors.New("pkcs7 format requires a certificate chain (-cert)")
+	}
+
+	sig, err := signer.Sign(ctx, payload)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	pub, err := signer.PublicKey(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	alg, err := AlgorithmForPublicKey(pub)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	certs := make([][]byte, len(certChain))
+	for i, c := range certChain {
+		certs[i] = c.Raw
+	}
+
+	blob, err := asn1.Marshal(pkcs7SignedData{
+		Algorithm:    alg,
+		Payload:      payload,
+		Signature:    sig,
+		Certificates: certs,
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("marshaling pkcs7 envelope: %w", err)
+	}
+	return blob, sig, nil, nil
+}
+
+func (*pkcs7Envelope) Unmarshal(blob []byte, _ map[string]string) ([]byte, []byte, []byte, error) {
+	var sd pkcs7SignedData
+	if _, err := asn1.Unmarshal(blob, &sd); err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing pkcs7 envelope: %w", err)
+	}
+	return sd.Payload, sd.Payload, sd.Signature, nil
+}
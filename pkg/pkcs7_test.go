@@ -0,0 +1,101 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestPKCS7EnvelopeRoundTrip(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	signer := &localSigner{signer: priv}
+	cert := selfSignedCert(t, pub, priv)
+
+	payload := []byte(`{"hello":"world"}`)
+	env := &pkcs7Envelope{}
+
+	blob, sig, annotations, err := env.Marshal(context.Background(), signer, payload, []*x509.Certificate{cert})
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if annotations != nil {
+		t.Errorf("Marshal() annotations = %v, want nil (pkcs7 carries everything in the envelope itself)", annotations)
+	}
+
+	gotPayload, signed, gotSig, err := env.Unmarshal(blob, annotations)
+	if err != nil {
+		t.Fatalf("Unmarshal() error: %v", err)
+	}
+	if !bytes.Equal(gotPayload, payload) {
+		t.Errorf("Unmarshal() payload = %q, want %q", gotPayload, payload)
+	}
+	if !bytes.Equal(signed, payload) {
+		t.Errorf("Unmarshal() signed = %q, want %q (pkcs7 signs the raw payload)", signed, payload)
+	}
+	if !bytes.Equal(gotSig, sig) {
+		t.Errorf("Unmarshal() signature = %x, want %x", gotSig, sig)
+	}
+	if !ed25519.Verify(pub, signed, gotSig) {
+		t.Error("signature did not verify against the recovered payload")
+	}
+}
+
+func TestPKCS7EnvelopeRequiresCertChain(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	_ = pub
+	signer := &localSigner{signer: priv}
+	env := &pkcs7Envelope{}
+
+	if _, _, _, err := env.Marshal(context.Background(), signer, []byte("payload"), nil); err == nil {
+		t.Error("Marshal() with no cert chain succeeded, want error")
+	}
+}
+
+// selfSignedCert builds a minimal self-signed certificate around pub, just
+// enough for pkcs7Envelope to embed in its Certificates field.
+func selfSignedCert(t *testing.T, pub ed25519.PublicKey, priv ed25519.PrivateKey) *x509.Certificate {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cosign-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, priv)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %v", err)
+	}
+	return cert
+}
@@ -0,0 +1,373 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy evaluates a declarative, containers/image-policy.json
+// style document against an image, so callers like admission controllers
+// can get a yes/no (and why) without shelling into cosign verify and
+// grepping its output.
+package policy
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+	"github.com/projectcosign/cosign/pkg"
+)
+
+// Requirement is a single condition a discovered signature must satisfy.
+// An empty Requirements list for a scope is treated as an implicit deny,
+// matching containers/image: a scope must say what it trusts.
+type Requirement struct {
+	// Type is "reject" (always fail this scope) or "signedBy" (at least
+	// one of KeyPaths/KeyDatas must verify a signature on the image).
+	Type string `json:"type"`
+
+	// KeyPaths are paths to PEM public keys, any one of which satisfies
+	// this requirement.
+	KeyPaths []string `json:"keyPaths,omitempty"`
+	// KeyDatas are PEM public keys inlined directly into the policy.
+	KeyDatas []string `json:"keyDatas,omitempty"`
+
+	// SignedIdentity additionally constrains what image reference the
+	// signed payload must name. Nil defaults to matchRepository.
+	SignedIdentity *SignedIdentity `json:"signedIdentity,omitempty"`
+}
+
+// SignedIdentity constrains a signed payload's docker-reference against
+// the reference the image was actually pulled by.
+type SignedIdentity struct {
+	// Type is one of matchRepository, matchExact, matchRemapIdentity.
+	Type string `json:"type"`
+
+	// Prefix/SignedPrefix are used by matchRemapIdentity: Prefix is
+	// rewritten to SignedPrefix in the pulled reference's repository
+	// before comparing it to the signed payload's.
+	Prefix       string `json:"prefix,omitempty"`
+	SignedPrefix string `json:"signedPrefix,omitempty"`
+}
+
+// RegistryPolicy scopes requirements to a registry, with an optional
+// per-repository override.
+type RegistryPolicy struct {
+	Default      []Requirement            `json:"default,omitempty"`
+	Repositories map[string][]Requirement `json:"repositories,omitempty"`
+}
+
+// Policy is the top-level policy document: a global default, overridden
+// per-registry and per-repository.
+type Policy struct {
+	Default    []Requirement             `json:"default,omitempty"`
+	Registries map[string]RegistryPolicy `json:"registries,omitempty"`
+}
+
+// Load reads and parses a policy document from path.
+func Load(path string) (*Policy, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy file: %w", err)
+	}
+	var p Policy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("parsing policy file: %w", err)
+	}
+	return &p, nil
+}
+
+// RequirementsFor returns the requirements that apply to ref: the
+// repository-scoped list if one exists, else the registry's default, else
+// the policy's top-level default.
+func (p *Policy) RequirementsFor(ref name.Reference) []Requirement {
+	reg, ok := p.Registries[ref.Context().RegistryStr()]
+	if !ok {
+		return p.Default
+	}
+	if reqs, ok := reg.Repositories[ref.Context().RepositoryStr()]; ok {
+		return reqs
+	}
+	if reg.Default != nil {
+		return reg.Default
+	}
+	return p.Default
+}
+
+// Signature is a signature discovered on an image, along with the payload
+// it covers.
+type Signature struct {
+	// Payload is the signed payload, used to recover the identity it was
+	// signed for.
+	Payload []byte
+	// Signed is the exact bytes Sig verifies. For most envelopes this is
+	// Payload itself, but e.g. DSSE signs its PAE encoding instead.
+	Signed []byte
+	Sig    []byte
+	// Annotations are the OCI layer annotations this signature was
+	// published with, e.g. a rekor.BundleAnnotationKey bundle, for
+	// TlogVerifierFunc to check transparency log inclusion against.
+	Annotations map[string]string
+}
+
+// DiscoverSignatures fetches every signature (in any registered
+// pkg.SignatureEnvelope format) cosign has pushed alongside ref's
+// resolved digest (the sha256-<digest> tag).
+func DiscoverSignatures(ctx context.Context, ref name.Reference) ([]Signature, error) {
+	get, err := remote.Get(ref, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return nil, fmt.Errorf("resolving image: %w", err)
+	}
+
+	munged := strings.ReplaceAll(get.Descriptor.Digest.String(), ":", "-")
+	sigTag := ref.Context().Tag(munged)
+
+	sigImg, err := remote.Image(sigTag, remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		// A 404 for the signature tag just means no signatures were
+		// found; anything else (auth failure, network timeout, a 5xx
+		// from the registry) is a real error an operator needs to see,
+		// not an implicit "no signatures".
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetching signature tag %s: %w", sigTag, err)
+	}
+	manifest, err := sigImg.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	layers, err := sigImg.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	var sigs []Signature
+	for i, l := range layers {
+		mt, err := l.MediaType()
+		if err != nil {
+			continue
+		}
+		env, ok := pkg.EnvelopeForMediaType(mt)
+		if !ok {
+			continue
+		}
+		rc, err := l.Uncompressed()
+		if err != nil {
+			continue
+		}
+		blob, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			continue
+		}
+		payload, signed, sig, err := env.Unmarshal(blob, manifest.Layers[i].Annotations)
+		if err != nil {
+			continue
+		}
+		sigs = append(sigs, Signature{Payload: payload, Signed: signed, Sig: sig, Annotations: manifest.Layers[i].Annotations})
+	}
+	return sigs, nil
+}
+
+// isNotFound reports whether err is a registry 404, e.g. for a signature tag
+// that was never pushed, as opposed to an auth failure, network timeout, or
+// 5xx that should be surfaced to the caller instead of read as "unsigned".
+func isNotFound(err error) bool {
+	var terr *transport.Error
+	return errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound
+}
+
+// Failure records why a single requirement was not satisfied.
+type Failure struct {
+	Requirement string
+	Reason      string
+}
+
+func (f Failure) String() string {
+	return fmt.Sprintf("%s: %s", f.Requirement, f.Reason)
+}
+
+// Result is the outcome of evaluating a Policy against an image: whether
+// it's allowed, and if not, every requirement that failed and why.
+type Result struct {
+	Allowed  bool
+	Failures []Failure
+}
+
+// VerifierFunc checks sig against payload under pub, the way
+// pkg.VerifySignature does. Evaluate takes it as a parameter rather than
+// calling pkg.VerifySignature itself.
+type VerifierFunc func(pub crypto.PublicKey, payload, sig []byte) error
+
+// PublicKeyLoaderFunc parses a PEM-encoded public key, whether read from a
+// keyPaths file or inlined as keyDatas, the way pkg.ParsePublicKeyPEM does.
+type PublicKeyLoaderFunc func(pemBytes []byte) (crypto.PublicKey, error)
+
+// TlogVerifierFunc additionally checks that sig's key was recorded in a
+// transparency log at sign time, the same check cosign verify-attestation
+// performs for DSSE attestations — so a signature that verifies offline
+// but whose key was never logged is still rejected. A nil TlogVerifierFunc
+// skips this step entirely, the same as when verify-attestation is run
+// without -rekor-url/COSIGN_EXPERIMENTAL.
+type TlogVerifierFunc func(sig Signature, pub crypto.PublicKey) error
+
+// Evaluate checks discovered signatures against the requirements that
+// apply to ref, aggregating across every signature: a signedBy
+// requirement is satisfied if any discovered signature satisfies it, even
+// if others don't. tlogVerify may be nil to skip transparency log checks.
+func Evaluate(ref name.Reference, requirements []Requirement, signatures []Signature, verify VerifierFunc, loadKey PublicKeyLoaderFunc, tlogVerify TlogVerifierFunc) *Result {
+	if len(requirements) == 0 {
+		return &Result{Allowed: false, Failures: []Failure{{
+			Requirement: "default",
+			Reason:      "no policy requirements matched this image; implicit deny",
+		}}}
+	}
+
+	var failures []Failure
+	for _, req := range requirements {
+		switch req.Type {
+		case "reject":
+			failures = append(failures, Failure{Requirement: "reject", Reason: "scope is configured to always reject"})
+		case "signedBy":
+			if ok, reason := satisfiesSignedBy(ref, req, signatures, verify, loadKey, tlogVerify); !ok {
+				failures = append(failures, Failure{Requirement: "signedBy", Reason: reason})
+			}
+		default:
+			failures = append(failures, Failure{Requirement: req.Type, Reason: "unknown requirement type"})
+		}
+	}
+	return &Result{Allowed: len(failures) == 0, Failures: failures}
+}
+
+func satisfiesSignedBy(ref name.Reference, req Requirement, signatures []Signature, verify VerifierFunc, loadKey PublicKeyLoaderFunc, tlogVerify TlogVerifierFunc) (bool, string) {
+	keys, err := loadKeys(req, loadKey)
+	if err != nil {
+		return false, err.Error()
+	}
+	if len(keys) == 0 {
+		return false, "signedBy requirement has no keyPaths or keyDatas"
+	}
+	if len(signatures) == 0 {
+		return false, "no signatures found on image"
+	}
+
+	sawTlogFailure := false
+	for _, sig := range signatures {
+		dockerRef, err := dockerReference(sig.Payload)
+		if err != nil {
+			continue
+		}
+		if !matchesIdentity(ref, dockerRef, req.SignedIdentity) {
+			continue
+		}
+		for _, key := range keys {
+			if verify(key, sig.Signed, sig.Sig) != nil {
+				continue
+			}
+			if tlogVerify == nil {
+				return true, ""
+			}
+			if err := tlogVerify(sig, key); err != nil {
+				sawTlogFailure = true
+				continue
+			}
+			return true, ""
+		}
+	}
+	if sawTlogFailure {
+		return false, "a signature verified against a configured key for a matching identity, but its key was not recorded in the transparency log"
+	}
+	return false, "no signature verified against the configured keys for a matching identity"
+}
+
+func loadKeys(req Requirement, loadKey PublicKeyLoaderFunc) ([]crypto.PublicKey, error) {
+	var keys []crypto.PublicKey
+	for _, path := range req.KeyPaths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading key %s: %w", path, err)
+		}
+		k, err := loadKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("parsing key %s: %w", path, err)
+		}
+		keys = append(keys, k)
+	}
+	for _, data := range req.KeyDatas {
+		k, err := loadKey([]byte(data))
+		if err != nil {
+			return nil, fmt.Errorf("parsing inline key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// dockerReferencePayload is the subset of pkg.SimpleContainerImage this
+// package needs: the identity a payload was signed for.
+type dockerReferencePayload struct {
+	Critical struct {
+		Identity struct {
+			DockerReference string `json:"docker-reference"`
+		} `json:"identity"`
+	} `json:"critical"`
+}
+
+func dockerReference(payload []byte) (string, error) {
+	var p dockerReferencePayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", err
+	}
+	return p.Critical.Identity.DockerReference, nil
+}
+
+// matchesIdentity checks signedRef (the docker-reference a payload was
+// signed for) against pulledRef (the reference the image was fetched by)
+// under the constraint si describes. A nil si (or one with an empty Type)
+// defaults to matchRepository; an empty docker-reference never matches.
+func matchesIdentity(pulledRef name.Reference, signedRef string, si *SignedIdentity) bool {
+	if signedRef == "" {
+		return false
+	}
+	signed, err := name.ParseReference(signedRef)
+	if err != nil {
+		return false
+	}
+
+	if si == nil || si.Type == "" || si.Type == "matchRepository" {
+		return signed.Context().Name() == pulledRef.Context().Name()
+	}
+
+	switch si.Type {
+	case "matchExact":
+		return signedRef == pulledRef.Name()
+	case "matchRemapIdentity":
+		pulledRepo := pulledRef.Context().Name()
+		if strings.HasPrefix(pulledRepo, si.Prefix) {
+			pulledRepo = si.SignedPrefix + strings.TrimPrefix(pulledRepo, si.Prefix)
+		}
+		return signed.Context().Name() == pulledRepo
+	default:
+		return false
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"crypto"
+	"errors"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+func mustRef(t *testing.T, s string) name.Reference {
+	t.Helper()
+	ref, err := name.ParseReference(s)
+	if err != nil {
+		t.Fatalf("parsing reference %q: %v", s, err)
+	}
+	return ref
+}
+
+func TestMatchesIdentity(t *testing.T) {
+	pulled := mustRef(t, "example.com/repo:latest")
+
+	tests := []struct {
+		name      string
+		signedRef string
+		si        *SignedIdentity
+		want      bool
+	}{
+		{
+			name:      "empty docker-reference never matches (what cosign sign without a fix would produce)",
+			signedRef: "",
+			si:        nil,
+			want:      false,
+		},
+		{
+			name:      "matchRepository default, same repo different tag",
+			signedRef: "example.com/repo:other-tag",
+			si:        nil,
+			want:      true,
+		},
+		{
+			name:      "matchRepository default, different repo",
+			signedRef: "example.com/other:latest",
+			si:        nil,
+			want:      false,
+		},
+		{
+			name:      "matchExact, exact match",
+			signedRef: "example.com/repo:latest",
+			si:        &SignedIdentity{Type: "matchExact"},
+			want:      true,
+		},
+		{
+			name:      "matchExact, different tag",
+			signedRef: "example.com/repo:other-tag",
+			si:        &SignedIdentity{Type: "matchExact"},
+			want:      false,
+		},
+		{
+			name:      "matchRemapIdentity, prefix rewritten",
+			signedRef: "internal.example.com/repo:latest",
+			si:        &SignedIdentity{Type: "matchRemapIdentity", Prefix: "example.com", SignedPrefix: "internal.example.com"},
+			want:      true,
+		},
+		{
+			name:      "matchRemapIdentity, prefix doesn't apply and repos differ",
+			signedRef: "example.com/other:latest",
+			si:        &SignedIdentity{Type: "matchRemapIdentity", Prefix: "other.example.com", SignedPrefix: "internal.example.com"},
+			want:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesIdentity(pulled, tt.signedRef, tt.si); got != tt.want {
+				t.Errorf("matchesIdentity(%q, %q, %+v) = %v, want %v", pulled, tt.signedRef, tt.si, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSatisfiesSignedByRequiresDockerReference(t *testing.T) {
+	ref := mustRef(t, "example.com/repo:latest")
+
+	// A signature whose payload never recorded a docker-reference (the bug
+	// this fix addresses: Payload() used to never populate it) must not
+	// satisfy a signedBy requirement, even though the signature itself is
+	// otherwise valid.
+	sigWithoutIdentity := Signature{
+		Payload: []byte(`{"critical":{"identity":{},"image":{"docker-manifest-digest":"sha256:deadbeef"},"type":"cosign container image signature"}}`),
+		Signed:  []byte("payload"),
+		Sig:     []byte("sig"),
+	}
+	// A signature whose payload does record the pulled reference's
+	// identity must satisfy the default matchRepository requirement.
+	sigWithIdentity := Signature{
+		Payload: []byte(`{"critical":{"identity":{"docker-reference":"example.com/repo:latest"},"image":{"docker-manifest-digest":"sha256:deadbeef"},"type":"cosign container image signature"}}`),
+		Signed:  []byte("payload"),
+		Sig:     []byte("sig"),
+	}
+
+	req := Requirement{Type: "signedBy", KeyDatas: []string{"unused"}}
+	verify := func(pub crypto.PublicKey, payload, sig []byte) error { return nil }
+	loadKey := func(pemBytes []byte) (crypto.PublicKey, error) { return "fake-key", nil }
+
+	if ok, reason := satisfiesSignedBy(ref, req, []Signature{sigWithoutIdentity}, verify, loadKey, nil); ok {
+		t.Errorf("satisfiesSignedBy() with no docker-reference on the signed payload = true, want false; reason: %s", reason)
+	}
+	if ok, reason := satisfiesSignedBy(ref, req, []Signature{sigWithIdentity}, verify, loadKey, nil); !ok {
+		t.Errorf("satisfiesSignedBy() with a matching docker-reference = false, want true; reason: %s", reason)
+	}
+
+	// An offline-valid signature whose key was never recorded in the
+	// transparency log must still fail the requirement once a
+	// TlogVerifierFunc is wired in.
+	tlogFails := func(Signature, crypto.PublicKey) error { return errors.New("key not found in log") }
+	if ok, reason := satisfiesSignedBy(ref, req, []Signature{sigWithIdentity}, verify, loadKey, tlogFails); ok {
+		t.Errorf("satisfiesSignedBy() with a failing TlogVerifierFunc = true, want false; reason: %s", reason)
+	}
+
+	tlogSucceeds := func(Signature, crypto.PublicKey) error { return nil }
+	if ok, reason := satisfiesSignedBy(ref, req, []Signature{sigWithIdentity}, verify, loadKey, tlogSucceeds); !ok {
+		t.Errorf("satisfiesSignedBy() with a passing TlogVerifierFunc = false, want true; reason: %s", reason)
+	}
+}
@@ -0,0 +1,465 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package rekor is a minimal client for the subset of the Rekor
+// transparency log API cosign needs: writing a signature (or DSSE
+// envelope) as a new entry, and reading an entry back by UUID or by the
+// hash of what it covers so a signature can be checked against the log.
+package rekor
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+)
+
+// DefaultServerURL is the public Rekor instance cosign talks to when
+// COSIGN_EXPERIMENTAL=1 is set without an explicit -rekor-url.
+const DefaultServerURL = "https://rekor.sigstore.dev"
+
+// BundleAnnotationKey is the OCI annotation a signed entry's inclusion
+// proof is carried under, so verify doesn't have to round-trip to the log
+// for every signature it already has a bundle for.
+const BundleAnnotationKey = "dev.sigstore.cosign/bundle"
+
+// IsExperimentalEnabled reports whether COSIGN_EXPERIMENTAL opts the
+// current invocation into Rekor by default.
+func IsExperimentalEnabled() bool {
+	return os.Getenv("COSIGN_EXPERIMENTAL") == "1"
+}
+
+// ServerURL returns flagVal, or DefaultServerURL if flagVal is empty.
+func ServerURL(flagVal string) string {
+	if flagVal != "" {
+		return flagVal
+	}
+	return DefaultServerURL
+}
+
+// Client talks to a single Rekor server.
+type Client struct {
+	serverURL  string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client for serverURL (or DefaultServerURL if empty).
+func NewClient(serverURL string) *Client {
+	return &Client{serverURL: ServerURL(serverURL), httpClient: http.DefaultClient}
+}
+
+// LogEntry is a single entry in the transparency log, as returned by the
+// "create entry" and "get entry" endpoints.
+type LogEntry struct {
+	UUID           string                `json:"-"`
+	Body           string                `json:"body"`
+	IntegratedTime int64                 `json:"integratedTime"`
+	LogID          string                `json:"logID"`
+	LogIndex       int64                 `json:"logIndex"`
+	Verification   *LogEntryVerification `json:"verification,omitempty"`
+}
+
+// LogEntryVerification carries the log's promise that it included this
+// entry: a signed entry timestamp (SET), an ECDSA signature over the rest
+// of the entry's fields.
+type LogEntryVerification struct {
+	SignedEntryTimestamp string `json:"signedEntryTimestamp"`
+}
+
+// Bundle is the shape cosign embeds as the dev.sigstore.cosign/bundle
+// annotation: just enough of a LogEntry to re-verify its SET offline
+// without calling back to the log.
+type Bundle struct {
+	SignedEntryTimestamp string        `json:"SignedEntryTimestamp"`
+	Payload              BundlePayload `json:"Payload"`
+}
+
+// BundlePayload is the part of a Bundle the SignedEntryTimestamp signs.
+type BundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogIndex       int64  `json:"logIndex"`
+	LogID          string `json:"logID"`
+}
+
+// Bundle extracts e's inclusion proof as a Bundle, for embedding as an
+// OCI annotation alongside the signature or attestation it covers.
+func (e *LogEntry) Bundle() (*Bundle, error) {
+	if e.Verification == nil || e.Verification.SignedEntryTimestamp == "" {
+		return nil, fmt.Errorf("rekor: log entry has no signed entry timestamp")
+	}
+	return &Bundle{
+		SignedEntryTimestamp: e.Verification.SignedEntryTimestamp,
+		Payload: BundlePayload{
+			Body:           e.Body,
+			IntegratedTime: e.IntegratedTime,
+			LogIndex:       e.LogIndex,
+			LogID:          e.LogID,
+		},
+	}, nil
+}
+
+// Annotations renders e's bundle as the single-entry annotation map sign
+// and attest upload alongside their signature/attestation layer.
+func (e *LogEntry) Annotations() (map[string]string, error) {
+	b, err := e.Bundle()
+	if err != nil {
+		return nil, err
+	}
+	bb, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: marshaling bundle: %w", err)
+	}
+	return map[string]string{BundleAnnotationKey: string(bb)}, nil
+}
+
+// ParseBundleAnnotation parses the dev.sigstore.cosign/bundle annotation
+// value back into a LogEntry with enough of its fields populated to
+// re-verify its SignedEntryTimestamp.
+func ParseBundleAnnotation(v string) (*LogEntry, error) {
+	var b Bundle
+	if err := json.Unmarshal([]byte(v), &b); err != nil {
+		return nil, fmt.Errorf("rekor: decoding bundle annotation: %w", err)
+	}
+	return &LogEntry{
+		Body:           b.Payload.Body,
+		IntegratedTime: b.Payload.IntegratedTime,
+		LogIndex:       b.Payload.LogIndex,
+		LogID:          b.Payload.LogID,
+		Verification:   &LogEntryVerification{SignedEntryTimestamp: b.SignedEntryTimestamp},
+	}, nil
+}
+
+// hashedRekordRequest is the body of a "hashedrekord" entry: a signature
+// over the hash of an opaque payload, the way cosign signs image payloads.
+type hashedRekordRequest struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Spec       struct {
+		Signature struct {
+			Content   string `json:"content"`
+			PublicKey struct {
+				Content string `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+	} `json:"spec"`
+}
+
+// UploadHashedRekord records a cosign image signature as a hashedrekord
+// entry: the sha256 of payload, the signature over it, and the public key
+// that verifies it.
+func (c *Client) UploadHashedRekord(ctx context.Context, payload, signature, pubKeyPEM []byte) (*LogEntry, error) {
+	digest := sha256.Sum256(payload)
+
+	var req hashedRekordRequest
+	req.Kind = "hashedrekord"
+	req.APIVersion = "0.0.1"
+	req.Spec.Signature.Content = base64.StdEncoding.EncodeToString(signature)
+	req.Spec.Signature.PublicKey.Content = base64.StdEncoding.EncodeToString(pubKeyPEM)
+	req.Spec.Data.Hash.Algorithm = "sha256"
+	req.Spec.Data.Hash.Value = hex.EncodeToString(digest[:])
+
+	return c.createEntry(ctx, req)
+}
+
+// intotoRequest is the body of an "intoto" entry: a DSSE envelope wrapping
+// an in-toto attestation, the way cosign attest signs predicates.
+type intotoRequest struct {
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+	Spec       struct {
+		Content struct {
+			Envelope  string `json:"envelope"`
+			PublicKey string `json:"publicKey"`
+		} `json:"content"`
+	} `json:"spec"`
+}
+
+// UploadDSSE records a DSSE-enveloped attestation as an intoto entry.
+func (c *Client) UploadDSSE(ctx context.Context, envelope, pubKeyPEM []byte) (*LogEntry, error) {
+	var req intotoRequest
+	req.Kind = "intoto"
+	req.APIVersion = "0.0.1"
+	req.Spec.Content.Envelope = base64.StdEncoding.EncodeToString(envelope)
+	req.Spec.Content.PublicKey = base64.StdEncoding.EncodeToString(pubKeyPEM)
+
+	return c.createEntry(ctx, req)
+}
+
+func (c *Client) createEntry(ctx context.Context, body interface{}) (*LogEntry, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: marshaling entry: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverURL+"/api/v1/log/entries", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: creating log entry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("rekor: creating log entry: %s: %s", resp.Status, string(rb))
+	}
+	return decodeSingleEntry(resp.Body)
+}
+
+// GetByUUID fetches the entry uniquely named uuid.
+func (c *Client) GetByUUID(ctx context.Context, uuid string) (*LogEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.serverURL+"/api/v1/log/entries/"+uuid, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: fetching log entry: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("rekor: fetching log entry %s: %s: %s", uuid, resp.Status, string(rb))
+	}
+	return decodeSingleEntry(resp.Body)
+}
+
+// decodeSingleEntry decodes Rekor's {uuid: LogEntry} response shape,
+// returning the one entry it contains with UUID populated.
+func decodeSingleEntry(r io.Reader) (*LogEntry, error) {
+	var entries map[string]LogEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("rekor: decoding log entry response: %w", err)
+	}
+	for uuid, e := range entries {
+		e := e
+		e.UUID = uuid
+		return &e, nil
+	}
+	return nil, fmt.Errorf("rekor: empty log entry response")
+}
+
+// SearchByHash returns the UUIDs of entries covering the sha256 digest
+// hash (hex-encoded, no "sha256:" prefix), newest first.
+func (c *Client) SearchByHash(ctx context.Context, hash string) ([]string, error) {
+	body, err := json.Marshal(map[string]string{"hash": "sha256:" + hash})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serverURL+"/api/v1/index/retrieve", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: searching log by hash: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		rb, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("rekor: searching log by hash: %s: %s", resp.Status, string(rb))
+	}
+	var uuids []string
+	if err := json.NewDecoder(resp.Body).Decode(&uuids); err != nil {
+		return nil, fmt.Errorf("rekor: decoding search response: %w", err)
+	}
+	return uuids, nil
+}
+
+// PublicKey fetches the log's own signing key, which verifies every
+// entry's SignedEntryTimestamp.
+func (c *Client) PublicKey(ctx context.Context) (crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.serverURL+"/api/v1/log/publicKey", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: fetching log public key: %w", err)
+	}
+	defer resp.Body.Close()
+	pb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("rekor: fetching log public key: %s: %s", resp.Status, string(pb))
+	}
+	p, _ := pem.Decode(pb)
+	if p == nil {
+		return nil, fmt.Errorf("rekor: log public key is not PEM encoded")
+	}
+	return x509.ParsePKIXPublicKey(p.Bytes)
+}
+
+// VerifyEntry finds the log entry covering content (identified by
+// contentHash, the sha256 digest it was indexed under when uploaded),
+// preferring a bundle embedded in annotations over a round-trip to the
+// log, then checks that the log really included it (via its
+// SignedEntryTimestamp) and that the entry was written against pub — so a
+// signature whose key was never recorded in the log is rejected even if
+// it verifies offline.
+func (c *Client) VerifyEntry(ctx context.Context, annotations map[string]string, contentHash [32]byte, pub crypto.PublicKey) error {
+	var entry *LogEntry
+	var err error
+	if b, ok := annotations[BundleAnnotationKey]; ok {
+		entry, err = ParseBundleAnnotation(b)
+	} else {
+		var uuids []string
+		uuids, err = c.SearchByHash(ctx, hex.EncodeToString(contentHash[:]))
+		if err == nil {
+			if len(uuids) == 0 {
+				return fmt.Errorf("rekor: no log entry found for this signature")
+			}
+			entry, err = c.GetByUUID(ctx, uuids[0])
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	logPub, err := c.PublicKey(ctx)
+	if err != nil {
+		return err
+	}
+	if err := VerifySET(logPub, entry); err != nil {
+		return err
+	}
+
+	entryPubPEM, err := entry.EntryPublicKeyPEM()
+	if err != nil {
+		return err
+	}
+	p, _ := pem.Decode(entryPubPEM)
+	if p == nil {
+		return fmt.Errorf("rekor: log entry public key is not PEM encoded")
+	}
+	entryPub, err := x509.ParsePKIXPublicKey(p.Bytes)
+	if err != nil {
+		return err
+	}
+	entryDER, err := x509.MarshalPKIXPublicKey(entryPub)
+	if err != nil {
+		return err
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(entryDER, pubDER) {
+		return fmt.Errorf("rekor: signing key was not the one recorded in the transparency log")
+	}
+	return nil
+}
+
+// EntryPublicKeyPEM extracts the PEM-encoded public key an entry's
+// signature was verified against at write time, regardless of whether it
+// was a hashedrekord or intoto entry.
+func (e *LogEntry) EntryPublicKeyPEM() ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(e.Body)
+	if err != nil {
+		return nil, fmt.Errorf("rekor: decoding entry body: %w", err)
+	}
+
+	var generic struct {
+		Spec struct {
+			Signature struct {
+				PublicKey struct {
+					Content string `json:"content"`
+				} `json:"publicKey"`
+			} `json:"signature"`
+			Content struct {
+				PublicKey string `json:"publicKey"`
+			} `json:"content"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, fmt.Errorf("rekor: decoding entry body: %w", err)
+	}
+
+	b64 := generic.Spec.Signature.PublicKey.Content
+	if b64 == "" {
+		b64 = generic.Spec.Content.PublicKey
+	}
+	if b64 == "" {
+		return nil, fmt.Errorf("rekor: entry body has no public key")
+	}
+	return base64.StdEncoding.DecodeString(b64)
+}
+
+// setPayload is the canonical form a LogEntry's SignedEntryTimestamp
+// signs: everything the log promised about the entry except the
+// signature itself.
+type setPayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+// VerifySET checks e's SignedEntryTimestamp against logPub, the log's own
+// public key, proving the log really did include e at the time it claims.
+func VerifySET(logPub crypto.PublicKey, e *LogEntry) error {
+	if e.Verification == nil || e.Verification.SignedEntryTimestamp == "" {
+		return fmt.Errorf("rekor: log entry has no signed entry timestamp")
+	}
+	sig, err := base64.StdEncoding.DecodeString(e.Verification.SignedEntryTimestamp)
+	if err != nil {
+		return fmt.Errorf("rekor: decoding signed entry timestamp: %w", err)
+	}
+	payload, err := json.Marshal(setPayload{
+		Body:           e.Body,
+		IntegratedTime: e.IntegratedTime,
+		LogID:          e.LogID,
+		LogIndex:       e.LogIndex,
+	})
+	if err != nil {
+		return err
+	}
+
+	ecPub, ok := logPub.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("rekor: unsupported log public key type: %T", logPub)
+	}
+	digest := sha256.Sum256(payload)
+	if !ecdsa.VerifyASN1(ecPub, digest[:], sig) {
+		return fmt.Errorf("rekor: signed entry timestamp verification failed")
+	}
+	return nil
+}
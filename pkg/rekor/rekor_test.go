@@ -0,0 +1,106 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rekor
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+)
+
+func signedEntry(t *testing.T, logKey *ecdsa.PrivateKey) *LogEntry {
+	t.Helper()
+	e := &LogEntry{
+		Body:           "dGVzdA==",
+		IntegratedTime: 1234,
+		LogID:          "test-log",
+		LogIndex:       5,
+	}
+	payload, err := json.Marshal(setPayload{
+		Body:           e.Body,
+		IntegratedTime: e.IntegratedTime,
+		LogID:          e.LogID,
+		LogIndex:       e.LogIndex,
+	})
+	if err != nil {
+		t.Fatalf("marshaling set payload: %v", err)
+	}
+	digest := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, logKey, digest[:])
+	if err != nil {
+		t.Fatalf("signing set payload: %v", err)
+	}
+	e.Verification = &LogEntryVerification{SignedEntryTimestamp: base64.StdEncoding.EncodeToString(sig)}
+	return e
+}
+
+func TestVerifySET(t *testing.T) {
+	logKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating log key: %v", err)
+	}
+	e := signedEntry(t, logKey)
+
+	if err := VerifySET(&logKey.PublicKey, e); err != nil {
+		t.Errorf("VerifySET() error: %v", err)
+	}
+
+	// A different log index changes the signed payload, so the same SET
+	// must no longer verify.
+	tampered := *e
+	tampered.LogIndex = 6
+	if err := VerifySET(&logKey.PublicKey, &tampered); err == nil {
+		t.Error("VerifySET() on a tampered entry succeeded, want error")
+	}
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating other key: %v", err)
+	}
+	if err := VerifySET(&otherKey.PublicKey, e); err == nil {
+		t.Error("VerifySET() against the wrong log key succeeded, want error")
+	}
+}
+
+func TestBundleRoundTrip(t *testing.T) {
+	logKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating log key: %v", err)
+	}
+	e := signedEntry(t, logKey)
+
+	annotations, err := e.Annotations()
+	if err != nil {
+		t.Fatalf("Annotations() error: %v", err)
+	}
+	v, ok := annotations[BundleAnnotationKey]
+	if !ok {
+		t.Fatalf("Annotations() missing %s", BundleAnnotationKey)
+	}
+
+	got, err := ParseBundleAnnotation(v)
+	if err != nil {
+		t.Fatalf("ParseBundleAnnotation() error: %v", err)
+	}
+	if err := VerifySET(&logKey.PublicKey, got); err != nil {
+		t.Errorf("VerifySET() on round-tripped bundle error: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/projectcosign/cosign/pkg/kms"
+)
+
+// Signer is satisfied by both a local, on-disk private key and a key held
+// in a remote KMS: everything cosign needs in order to sign a payload.
+type Signer interface {
+	Sign(ctx context.Context, payload []byte) ([]byte, error)
+	PublicKey(ctx context.Context) (crypto.PublicKey, error)
+}
+
+// localSigner adapts an in-memory crypto.Signer to the Signer interface,
+// the way the standard library's x509 package expects to sign: ed25519
+// signs the message directly, everything else (RSA, ECDSA P-256/P-384)
+// signs a SHA-256 digest of it.
+type localSigner struct {
+	signer crypto.Signer
+}
+
+func (l *localSigner) Sign(_ context.Context, payload []byte) ([]byte, error) {
+	if _, ok := l.signer.Public().(ed25519.PublicKey); ok {
+		return l.signer.Sign(rand.Reader, payload, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(payload)
+	return l.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (l *localSigner) PublicKey(_ context.Context) (crypto.PublicKey, error) {
+	return l.signer.Public(), nil
+}
+
+// SignerFromKeyRef resolves keyRef to a Signer. If keyRef is a KMS URI
+// (e.g. gcpkms://, awskms://, hashivault://) it's resolved against the kms
+// provider registry and signing happens remotely; otherwise it's treated
+// as a path to an on-disk, password-encrypted ed25519 private key.
+func SignerFromKeyRef(ctx context.Context, keyRef string, pass []byte) (Signer, error) {
+	if kms.IsRef(keyRef) {
+		return kms.Get(ctx, keyRef)
+	}
+
+	pk, err := LoadPrivateKey(keyRef, pass)
+	if err != nil {
+		return nil, err
+	}
+	return &localSigner{signer: pk}, nil
+}
+
+// AlgorithmForPublicKey names the signing algorithm pub verifies,
+// matching VerifySignature's dispatch, so envelope formats that record
+// the algorithm they were signed with (e.g. PKCS#7) agree with it.
+func AlgorithmForPublicKey(pub crypto.PublicKey) (string, error) {
+	switch pub.(type) {
+	case ed25519.PublicKey:
+		return "ed25519", nil
+	case *ecdsa.PublicKey:
+		return "ecdsa-sha256", nil
+	case *rsa.PublicKey:
+		return "rsa-sha256", nil
+	default:
+		return "", fmt.Errorf("unsupported public key type: %T", pub)
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// staticLayer is a v1.Layer backed by an in-memory blob.
+type staticLayer struct {
+	b  []byte
+	mt types.MediaType
+}
+
+func (s *staticLayer) Digest() (v1.Hash, error) {
+	h, _, err := v1.SHA256(bytes.NewReader(s.b))
+	return h, err
+}
+
+func (s *staticLayer) DiffID() (v1.Hash, error) { return s.Digest() }
+
+func (s *staticLayer) Compressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(s.b)), nil
+}
+
+func (s *staticLayer) Uncompressed() (io.ReadCloser, error) {
+	return ioutil.NopCloser(bytes.NewReader(s.b)), nil
+}
+
+func (s *staticLayer) Size() (int64, error) { return int64(len(s.b)), nil }
+
+func (s *staticLayer) MediaType() (types.MediaType, error) { return s.mt, nil }
+
+// newStaticLayer wraps b as a single-blob v1.Layer with the given media type.
+func newStaticLayer(b []byte, mt types.MediaType) v1.Layer {
+	return &staticLayer{b: b, mt: mt}
+}
+
+const (
+	// SignatureAnnotationKey carries the base64-encoded payload that the
+	// signature layer's contents sign.
+	SignatureAnnotationKey = "dev.cosignproject.cosign/signature"
+)
+
+const (
+	// AttestationMediaType is the layer media type attestations (DSSE
+	// envelopes) are uploaded as, distinct from a signature layer's media
+	// type so the two kinds of artifact can be tagged side-by-side.
+	AttestationMediaType = "application/vnd.dev.cosignproject.cosign.attestation.v1+json"
+)
+
+// UploadAttestation uploads a DSSE envelope as an OCI artifact to dst (cosign
+// attest tags it sha256-<digest>.att, next to the signature's sha256-<digest>
+// tag). annotations carries
+// extra OCI annotations (e.g. a Rekor bundle) to attach to the layer.
+func UploadAttestation(env *Envelope, annotations map[string]string, dst name.Tag) error {
+	b, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("marshaling attestation envelope: %w", err)
+	}
+
+	l := newStaticLayer(b, types.MediaType(AttestationMediaType))
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: l, Annotations: annotations})
+	if err != nil {
+		return fmt.Errorf("building attestation image: %w", err)
+	}
+
+	return remote.Write(dst, img, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+}
+
+// PublishSignature uploads blob, a SignatureEnvelope's Marshal output, as
+// dst's single layer with the given media type and annotations. Every
+// envelope format is published this way; only the envelope's own Marshal
+// step differs.
+func PublishSignature(blob []byte, mt types.MediaType, annotations map[string]string, dst name.Tag) error {
+	l := newStaticLayer(blob, mt)
+	img, err := mutate.Append(empty.Image, mutate.Addendum{
+		Layer:       l,
+		Annotations: annotations,
+	})
+	if err != nil {
+		return fmt.Errorf("building signature image: %w", err)
+	}
+
+	return remote.Write(dst, img, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+}
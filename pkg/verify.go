@@ -0,0 +1,101 @@
+/*
+Copyright The Cosign Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/projectcosign/cosign/pkg/kms"
+)
+
+// LoadPublicKey reads a PEM-encoded, PKIX-formatted public key from path.
+func LoadPublicKey(path string) (crypto.PublicKey, error) {
+	kb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading public key file: %w", err)
+	}
+	return ParsePublicKeyPEM(kb)
+}
+
+// ParsePublicKeyPEM parses a PEM-encoded, PKIX-formatted public key from
+// pemBytes, the in-memory counterpart to LoadPublicKey.
+func ParsePublicKeyPEM(pemBytes []byte) (crypto.PublicKey, error) {
+	p, _ := pem.Decode(pemBytes)
+	if p == nil {
+		return nil, errors.New("invalid pem block")
+	}
+	return x509.ParsePKIXPublicKey(p.Bytes)
+}
+
+// MarshalPublicKeyPEM PEM-encodes pub as a PKIX public key, the same
+// format LoadPublicKey reads back.
+func MarshalPublicKeyPEM(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling public key: %w", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// PublicKeyFromKeyRef resolves keyRef (a KMS URI or a path to a PEM public
+// key) to the public key it names.
+func PublicKeyFromKeyRef(ctx context.Context, keyRef string) (crypto.PublicKey, error) {
+	if kms.IsRef(keyRef) {
+		signer, err := kms.Get(ctx, keyRef)
+		if err != nil {
+			return nil, err
+		}
+		return signer.PublicKey(ctx)
+	}
+	return LoadPublicKey(keyRef)
+}
+
+// VerifySignature checks sig against payload under pub, dispatching on the
+// concrete public key type the way cosign's KMS providers hand them back.
+func VerifySignature(pub crypto.PublicKey, payload, sig []byte) error {
+	switch k := pub.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(k, payload, sig) {
+			return errors.New("ed25519 signature verification failed")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		if !ecdsa.VerifyASN1(k, digest[:], sig) {
+			return errors.New("ecdsa signature verification failed")
+		}
+		return nil
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(payload)
+		if err := rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig); err != nil {
+			return fmt.Errorf("rsa signature verification failed: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type: %T", pub)
+	}
+}